@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package apparmor contains basic interactions with the AppArmor
+// kernel LSM and the apparmor_parser tool used to load and unload
+// profiles into it. The platform-specific pieces live in
+// apparmor_linux.go and apparmor_unsupported.go; this file holds the
+// types and helpers that do not depend on actually talking to the
+// kernel.
+package apparmor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ubuntu-core/snappy/snap"
+)
+
+// profilesPath points at the file exposing profiles currently loaded
+// into the kernel. It is a variable so that it can be overridden in
+// tests via MockProfilesPath.
+var profilesPath = "/sys/kernel/security/apparmor/profiles"
+
+// enabledPath points at the file exposing whether the kernel was
+// built with AppArmor support turned on.
+var enabledPath = "/sys/module/apparmor/parameters/enabled"
+
+// cacheDir is where apparmor_parser keeps its compiled profile cache.
+const cacheDir = "/var/cache/apparmor"
+
+// ErrAppArmorUnsupported is returned by LoadProfile, Profile.Unload
+// and LoadedProfiles when run on a system that has no usable AppArmor
+// support, as reported by HostSupportsAppArmor.
+var ErrAppArmorUnsupported = errors.New("apparmor support is not enabled")
+
+// ErrProfileNotLoaded is returned by LoadProfile when apparmor_parser
+// exits successfully but the profile is not found among the profiles
+// loaded into the kernel afterwards.
+type ErrProfileNotLoaded struct {
+	Name string
+}
+
+func (e *ErrProfileNotLoaded) Error() string {
+	return fmt.Sprintf("apparmor_parser succeeded but profile %q is not loaded", e.Name)
+}
+
+// Profile describes a single profile loaded into the kernel.
+type Profile struct {
+	Name string
+	Mode string
+}
+
+// ProfileName returns the apparmor profile name used for the given
+// snap application, e.g. "snap.SNAP.APP".
+func ProfileName(appInfo *snap.AppInfo) string {
+	return fmt.Sprintf("snap.%s.%s", appInfo.Snap.Name, appInfo.Name)
+}
@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HostSupportsAppArmor returns true if the current kernel has
+// AppArmor enabled. When this returns false LoadProfile, LoadProfiles,
+// Profile.Unload, UnloadProfiles, LoadedProfiles and IsLoaded return
+// ErrAppArmorUnsupported instead of attempting to talk to a kernel
+// interface that is not there; PrecompileProfiles does not check it.
+func HostSupportsAppArmor() bool {
+	enabled, err := IsEnabled()
+	return err == nil && enabled
+}
+
+// IsEnabled returns true if AppArmor is enabled at the kernel level.
+func IsEnabled() (bool, error) {
+	content, err := ioutil.ReadFile(enabledPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(content)) == "Y", nil
+}
+
+// IsLoaded returns true if a profile with the given name is currently
+// loaded into the kernel.
+func IsLoaded(name string) (bool, error) {
+	profiles, err := LoadedProfiles()
+	if err != nil {
+		return false, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LoadProfile loads an apparmor profile from the given file into the
+// kernel, replacing any previous profile of the same name. Once
+// apparmor_parser exits successfully it double-checks that the
+// profile actually made it into the kernel, since a clean exit status
+// alone does not guarantee that.
+func LoadProfile(fname string) error {
+	if !HostSupportsAppArmor() {
+		return ErrAppArmorUnsupported
+	}
+
+	output, err := exec.Command("apparmor_parser",
+		"--replace", "--write-cache", "-O", "no-expr-simplify",
+		fmt.Sprintf("--cache-loc=%s", cacheDir), fname).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot load apparmor profile: %s\napparmor_parser output:\n%s", err, output)
+	}
+
+	name := filepath.Base(fname)
+	loaded, err := IsLoaded(name)
+	if err != nil {
+		return err
+	}
+	if !loaded {
+		return &ErrProfileNotLoaded{Name: name}
+	}
+	return nil
+}
+
+// Unload removes the profile from the kernel.
+func (p *Profile) Unload() error {
+	if !HostSupportsAppArmor() {
+		return ErrAppArmorUnsupported
+	}
+
+	output, err := exec.Command("apparmor_parser", "--remove", p.Name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot unload apparmor profile: %s\napparmor_parser output:\n%s", err, output)
+	}
+	return nil
+}
+
+// LoadedProfiles lists the snap-related profiles currently loaded into
+// the kernel, as reported via profilesPath.
+func LoadedProfiles() ([]Profile, error) {
+	if !HostSupportsAppArmor() {
+		return nil, ErrAppArmorUnsupported
+	}
+
+	file, err := os.Open(profilesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseProfiles(file)
+}
+
+// parseProfiles parses the contents of
+// /sys/kernel/security/apparmor/profiles, keeping only the
+// snap-related entries. Each line is expected to look like
+// "name (mode)".
+func parseProfiles(reader io.Reader) ([]Profile, error) {
+	var profiles []Profile
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		open := strings.LastIndex(line, " (")
+		if open == -1 || !strings.HasSuffix(line, ")") {
+			return nil, fmt.Errorf("syntax error, expected: name (mode)")
+		}
+		name := line[:open]
+		mode := line[open+2 : len(line)-1]
+		if strings.HasPrefix(name, "snap.") {
+			profiles = append(profiles, Profile{Name: name, Mode: mode})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
@@ -38,6 +38,7 @@ func Test(t *testing.T) {
 type appArmorSuite struct {
 	testutil.BaseTest
 	profilesFilename string
+	enabledFilename  string
 }
 
 var _ = Suite(&appArmorSuite{})
@@ -47,6 +48,10 @@ func (s *appArmorSuite) SetUpTest(c *C) {
 	// Mock the list of profiles in the running kernel
 	s.profilesFilename = path.Join(c.MkDir(), "profiles")
 	apparmor.MockProfilesPath(&s.BaseTest, s.profilesFilename)
+	// Pretend AppArmor is enabled unless a test says otherwise.
+	s.enabledFilename = path.Join(c.MkDir(), "enabled")
+	ioutil.WriteFile(s.enabledFilename, []byte("Y\n"), 0600)
+	apparmor.MockEnabledPath(&s.BaseTest, s.enabledFilename)
 }
 
 // Tests for LoadProfile()
@@ -54,10 +59,14 @@ func (s *appArmorSuite) SetUpTest(c *C) {
 func (s *appArmorSuite) TestLoadProfileRunsAppArmorParserReplace(c *C) {
 	cmd := testutil.MockCommand(c, "apparmor_parser", "")
 	defer cmd.Restore()
-	err := apparmor.LoadProfile("foo.snap")
+	// LoadedProfiles (used by the post-load check below) only reports
+	// "snap."-prefixed entries, so the fixture name must follow that
+	// convention too, just like real profile names do.
+	ioutil.WriteFile(s.profilesFilename, []byte("snap.foo.bar (enforce)\n"), 0600)
+	err := apparmor.LoadProfile("snap.foo.bar")
 	c.Assert(err, IsNil)
 	c.Assert(cmd.Calls(), DeepEquals, []string{
-		"--replace --write-cache -O no-expr-simplify --cache-loc=/var/cache/apparmor foo.snap"})
+		"--replace --write-cache -O no-expr-simplify --cache-loc=/var/cache/apparmor snap.foo.bar"})
 }
 
 func (s *appArmorSuite) TestLoadProfileReportsErrors(c *C) {
@@ -71,6 +80,59 @@ apparmor_parser output:
 		"--replace --write-cache -O no-expr-simplify --cache-loc=/var/cache/apparmor foo.snap"})
 }
 
+func (s *appArmorSuite) TestLoadProfileReportsNotLoaded(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	// apparmor_parser exits 0 but the profile never shows up in the
+	// kernel's list of loaded profiles.
+	ioutil.WriteFile(s.profilesFilename, []byte(""), 0600)
+	err := apparmor.LoadProfile("foo.snap")
+	c.Assert(err, ErrorMatches, `apparmor_parser succeeded but profile "foo.snap" is not loaded`)
+}
+
+// Tests for IsEnabled()
+
+func (s *appArmorSuite) TestIsEnabledTrue(c *C) {
+	enabledFilename := path.Join(c.MkDir(), "enabled")
+	apparmor.MockEnabledPath(&s.BaseTest, enabledFilename)
+	ioutil.WriteFile(enabledFilename, []byte("Y\n"), 0600)
+	enabled, err := apparmor.IsEnabled()
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, true)
+}
+
+func (s *appArmorSuite) TestIsEnabledFalse(c *C) {
+	enabledFilename := path.Join(c.MkDir(), "enabled")
+	apparmor.MockEnabledPath(&s.BaseTest, enabledFilename)
+	ioutil.WriteFile(enabledFilename, []byte("N\n"), 0600)
+	enabled, err := apparmor.IsEnabled()
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, false)
+}
+
+func (s *appArmorSuite) TestIsEnabledMissingFile(c *C) {
+	apparmor.MockEnabledPath(&s.BaseTest, path.Join(c.MkDir(), "enabled"))
+	enabled, err := apparmor.IsEnabled()
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, false)
+}
+
+// Tests for IsLoaded()
+
+func (s *appArmorSuite) TestIsLoadedTrue(c *C) {
+	ioutil.WriteFile(s.profilesFilename, []byte("snap.foo.bar (enforce)\n"), 0600)
+	loaded, err := apparmor.IsLoaded("snap.foo.bar")
+	c.Assert(err, IsNil)
+	c.Check(loaded, Equals, true)
+}
+
+func (s *appArmorSuite) TestIsLoadedFalse(c *C) {
+	ioutil.WriteFile(s.profilesFilename, []byte("snap.foo.bar (enforce)\n"), 0600)
+	loaded, err := apparmor.IsLoaded("snap.other.baz")
+	c.Assert(err, IsNil)
+	c.Check(loaded, Equals, false)
+}
+
 // Tests for Profile.Unload()
 
 func (s *appArmorSuite) TestUnloadProfileRunsAppArmorParserRemove(c *C) {
@@ -141,7 +203,7 @@ webbrowser-app//oxide_helper (enforce)
 func (s *appArmorSuite) TestLoadedApparmorProfilesHandlesParsingErrors(c *C) {
 	ioutil.WriteFile(s.profilesFilename, []byte("broken stuff here\n"), 0600)
 	profiles, err := apparmor.LoadedProfiles()
-	c.Assert(err, ErrorMatches, "newline in format does not match input")
+	c.Assert(err, ErrorMatches, `syntax error, expected: name \(mode\)`)
 	c.Check(profiles, IsNil)
 	ioutil.WriteFile(s.profilesFilename, []byte("truncated"), 0600)
 	profiles, err = apparmor.LoadedProfiles()
@@ -153,3 +215,82 @@ func (s *appArmorSuite) TestProfileName(c *C) {
 	appInfo := &snap.AppInfo{Snap: &snap.Info{Name: "SNAP"}, Name: "APP"}
 	c.Assert(apparmor.ProfileName(appInfo), Equals, "snap.SNAP.APP")
 }
+
+// Tests for LoadProfiles(), UnloadProfiles() and PrecompileProfiles()
+
+func (s *appArmorSuite) TestLoadProfilesRunsOneBatch(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	err := apparmor.LoadProfiles([]string{"foo.snap", "bar.snap"})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, []string{
+		"--replace --write-cache -O no-expr-simplify --cache-loc=/var/cache/apparmor foo.snap bar.snap"})
+}
+
+func (s *appArmorSuite) TestLoadProfilesReportsErrors(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "exit 42")
+	defer cmd.Restore()
+	err := apparmor.LoadProfiles([]string{"foo.snap"})
+	c.Assert(err.Error(), Equals, `cannot run apparmor_parser: exit status 42
+apparmor_parser output:
+`)
+}
+
+func (s *appArmorSuite) TestLoadProfilesShardsAcrossConcurrency(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	err := apparmor.LoadProfiles([]string{"a.snap", "b.snap", "c.snap", "d.snap"}, apparmor.Concurrency(2))
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), HasLen, 2)
+}
+
+func (s *appArmorSuite) TestUnloadProfilesRunsAppArmorParserRemove(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	err := apparmor.UnloadProfiles([]string{"foo.snap", "bar.snap"})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, []string{"--remove foo.snap bar.snap"})
+}
+
+func (s *appArmorSuite) TestPrecompileProfilesSkipsKernelLoad(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	err := apparmor.PrecompileProfiles([]string{"foo.snap"})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, []string{
+		"--skip-kernel-load --write-cache -O no-expr-simplify -Q --cache-loc=/var/cache/apparmor foo.snap"})
+}
+
+// Tests for HostSupportsAppArmor() and ErrAppArmorUnsupported
+
+func (s *appArmorSuite) TestPrecompileProfilesWorksWithoutAppArmor(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	// PrecompileProfiles never loads anything into the kernel, so it
+	// must keep working on build/seed hosts with no AppArmor LSM at
+	// all, e.g. a cross-arch build chroot.
+	ioutil.WriteFile(s.enabledFilename, []byte("N\n"), 0600)
+	c.Check(apparmor.HostSupportsAppArmor(), Equals, false)
+	err := apparmor.PrecompileProfiles([]string{"foo.snap"})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Calls(), DeepEquals, []string{
+		"--skip-kernel-load --write-cache -O no-expr-simplify -Q --cache-loc=/var/cache/apparmor foo.snap"})
+}
+
+func (s *appArmorSuite) TestLoadProfileUnsupported(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	ioutil.WriteFile(s.enabledFilename, []byte("N\n"), 0600)
+	c.Check(apparmor.HostSupportsAppArmor(), Equals, false)
+	err := apparmor.LoadProfile("foo.snap")
+	c.Assert(err, Equals, apparmor.ErrAppArmorUnsupported)
+	c.Check(cmd.Calls(), HasLen, 0)
+}
+
+func (s *appArmorSuite) TestLoadProfilesEmptyIsNoop(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "exit 1")
+	defer cmd.Restore()
+	err := apparmor.LoadProfiles(nil)
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), HasLen, 0)
+}
@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+//go:build !linux
+// +build !linux
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+// HostSupportsAppArmor always returns false on platforms that have no
+// AppArmor LSM to begin with.
+func HostSupportsAppArmor() bool {
+	return false
+}
+
+// IsEnabled always returns false outside of Linux.
+func IsEnabled() (bool, error) {
+	return false, nil
+}
+
+// IsLoaded always returns ErrAppArmorUnsupported outside of Linux.
+func IsLoaded(name string) (bool, error) {
+	return false, ErrAppArmorUnsupported
+}
+
+// LoadProfile returns ErrAppArmorUnsupported outside of Linux, rather
+// than failing with a missing apparmor_parser binary.
+func LoadProfile(fname string) error {
+	return ErrAppArmorUnsupported
+}
+
+// Unload returns ErrAppArmorUnsupported outside of Linux.
+func (p *Profile) Unload() error {
+	return ErrAppArmorUnsupported
+}
+
+// LoadedProfiles returns ErrAppArmorUnsupported outside of Linux.
+func LoadedProfiles() ([]Profile, error) {
+	return nil, ErrAppArmorUnsupported
+}
@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+// BatchOption customizes how LoadProfiles, UnloadProfiles and
+// PrecompileProfiles shard their work across apparmor_parser
+// invocations.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	concurrency int
+}
+
+// Concurrency shards paths across n parallel apparmor_parser
+// processes instead of a single one. The default, used when this
+// option is not given, is one process for the whole batch.
+func Concurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = n
+	}
+}
+
+func newBatchOptions(opts []BatchOption) *batchOptions {
+	o := &batchOptions{concurrency: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+	return o
+}
+
+// shard splits paths into up to n roughly-equal, non-empty chunks.
+func shard(paths []string, n int) [][]string {
+	if n > len(paths) {
+		n = len(paths)
+	}
+	if n < 1 {
+		return nil
+	}
+	chunks := make([][]string, n)
+	for i, p := range paths {
+		chunks[i%n] = append(chunks[i%n], p)
+	}
+	return chunks
+}
+
+// runParserBatches invokes apparmor_parser once per shard of paths,
+// in parallel, passing extraArgs ahead of the paths in each
+// invocation. If any invocation fails it returns the error, including
+// the parser output, of the first failed shard; the errors of other
+// failed shards are not reported.
+func runParserBatches(paths []string, extraArgs []string, opts []BatchOption) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	o := newBatchOptions(opts)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(shard(paths, o.concurrency)))
+	for i, chunk := range shard(paths, o.concurrency) {
+		wg.Add(1)
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			args := append(append([]string{}, extraArgs...), chunk...)
+			if output, err := exec.Command("apparmor_parser", args...).CombinedOutput(); err != nil {
+				errs[i] = fmt.Errorf("cannot run apparmor_parser: %s\napparmor_parser output:\n%s", err, output)
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadProfiles loads every profile in paths into the kernel in as few
+// apparmor_parser invocations as possible, reusing the on-disk
+// profile cache. This is considerably cheaper than calling LoadProfile
+// once per path when installing or refreshing a snap with many apps
+// and hooks.
+func LoadProfiles(paths []string, opts ...BatchOption) error {
+	if len(paths) > 0 && !HostSupportsAppArmor() {
+		return ErrAppArmorUnsupported
+	}
+	extraArgs := []string{
+		"--replace", "--write-cache", "-O", "no-expr-simplify",
+		fmt.Sprintf("--cache-loc=%s", cacheDir),
+	}
+	return runParserBatches(paths, extraArgs, opts)
+}
+
+// UnloadProfiles removes every named profile from the kernel in as
+// few apparmor_parser invocations as possible.
+func UnloadProfiles(names []string, opts ...BatchOption) error {
+	if len(names) > 0 && !HostSupportsAppArmor() {
+		return ErrAppArmorUnsupported
+	}
+	return runParserBatches(names, []string{"--remove"}, opts)
+}
+
+// PrecompileProfiles warms the apparmor_parser cache for every profile
+// in paths without loading them into the kernel. This is useful during
+// snap build and seeding, where profiles are compiled ahead of time
+// but must not be loaded on the build host. Unlike LoadProfiles and
+// UnloadProfiles it never touches the kernel, so it does not require
+// HostSupportsAppArmor: it runs equally well on a build chroot or CI
+// image with no AppArmor LSM at all, as long as apparmor_parser itself
+// is installed.
+func PrecompileProfiles(paths []string, opts ...BatchOption) error {
+	extraArgs := []string{
+		"--skip-kernel-load", "--write-cache", "-O", "no-expr-simplify", "-Q",
+		fmt.Sprintf("--cache-loc=%s", cacheDir),
+	}
+	return runParserBatches(paths, extraArgs, opts)
+}
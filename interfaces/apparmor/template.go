@@ -0,0 +1,123 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	"github.com/ubuntu-core/snappy/osutil"
+	"github.com/ubuntu-core/snappy/snap"
+)
+
+// ProfileTemplate holds the information needed to render the default
+// AppArmor profile for a single snap application.
+type ProfileTemplate struct {
+	// Name is the apparmor profile name, e.g. "snap.SNAP.APP".
+	Name string
+	// Executable is the absolute path of the confined binary.
+	Executable string
+	// Rules holds additional policy lines, typically contributed by
+	// the interfaces connected to the snap's plugs and slots, that
+	// are appended verbatim to the generated profile.
+	Rules []string
+}
+
+// Option customizes the ProfileTemplate built by GenerateProfile.
+type Option func(*ProfileTemplate)
+
+// WithRules appends the given policy rules to the profile.
+func WithRules(rules ...string) Option {
+	return func(t *ProfileTemplate) {
+		t.Rules = append(t.Rules, rules...)
+	}
+}
+
+// defaultTemplate is the skeleton used for every snap application
+// profile. Interfaces contribute additional rules via WithRules.
+const defaultTemplate = `
+# This is a default, restrictive AppArmor profile for snap applications,
+# generated by snapd. Do not edit; it will be overwritten on the next
+# refresh.
+#include <tunables/global>
+
+profile "{{.Name}}" (attach_disconnected) {
+  #include <abstractions/base>
+
+  {{.Executable}} ixr,
+{{range .Rules}}
+  {{.}}
+{{end}}
+}
+`
+
+var profileTemplate = template.Must(template.New("apparmor-profile").Parse(defaultTemplate))
+
+// GenerateProfile renders the default AppArmor profile for the given
+// snap application. The profile names the snap with its identity
+// (snap.SNAP.APP), confines its binary and includes any rules merged
+// in via opts, typically contributed by connected interfaces.
+func GenerateProfile(appInfo *snap.AppInfo, opts ...Option) ([]byte, error) {
+	t := &ProfileTemplate{
+		Name:       ProfileName(appInfo),
+		Executable: filepath.Join(appInfo.Snap.MountDir(), appInfo.Command),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	var buf bytes.Buffer
+	if err := profileTemplate.Execute(&buf, t); err != nil {
+		return nil, fmt.Errorf("cannot generate apparmor profile for %q: %s", t.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteProfile writes the rendered profile content for appInfo into
+// dir, naming the file after the profile, so that it can later be
+// loaded with LoadProfile.
+func WriteProfile(dir string, appInfo *snap.AppInfo, content []byte) (string, error) {
+	fname := filepath.Join(dir, ProfileName(appInfo))
+	if err := osutil.AtomicWriteFile(fname, content, 0644, 0); err != nil {
+		return "", fmt.Errorf("cannot write apparmor profile %q: %s", fname, err)
+	}
+	return fname, nil
+}
+
+// SetupProfile generates, writes and loads the default AppArmor
+// profile for appInfo, giving the security backend a single call that
+// goes from a snap.AppInfo to a profile loaded into the kernel. It
+// returns the path of the profile file written to dir.
+func SetupProfile(dir string, appInfo *snap.AppInfo, opts ...Option) (string, error) {
+	content, err := GenerateProfile(appInfo, opts...)
+	if err != nil {
+		return "", err
+	}
+	fname, err := WriteProfile(dir, appInfo, content)
+	if err != nil {
+		return "", err
+	}
+	if err := LoadProfile(fname); err != nil {
+		return "", err
+	}
+	return fname, nil
+}
@@ -0,0 +1,102 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package apparmor_test
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/ubuntu-core/snappy/interfaces/apparmor"
+	"github.com/ubuntu-core/snappy/snap"
+	"github.com/ubuntu-core/snappy/testutil"
+)
+
+// Tests for GenerateProfile()
+
+func (s *appArmorSuite) appInfo(c *C) *snap.AppInfo {
+	return &snap.AppInfo{
+		Snap:    &snap.Info{Name: "SNAP"},
+		Name:    "APP",
+		Command: "bin/app",
+	}
+}
+
+func (s *appArmorSuite) TestGenerateProfileNamesAndConfinesTheApp(c *C) {
+	appInfo := s.appInfo(c)
+	content, err := apparmor.GenerateProfile(appInfo)
+	c.Assert(err, IsNil)
+	c.Check(string(content), testutil.Contains, `profile "snap.SNAP.APP" (attach_disconnected) {`)
+	c.Check(string(content), testutil.Contains, filepath.Join(appInfo.Snap.MountDir(), "bin/app")+" ixr,")
+}
+
+func (s *appArmorSuite) TestGenerateProfileMergesRulesFromWithRules(c *C) {
+	content, err := apparmor.GenerateProfile(s.appInfo(c),
+		apparmor.WithRules("/dev/foo rw,"),
+		apparmor.WithRules("network bluetooth,"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), testutil.Contains, "/dev/foo rw,")
+	c.Check(string(content), testutil.Contains, "network bluetooth,")
+}
+
+// Tests for WriteProfile()
+
+func (s *appArmorSuite) TestWriteProfileWritesNamedFile(c *C) {
+	dir := c.MkDir()
+	appInfo := s.appInfo(c)
+	fname, err := apparmor.WriteProfile(dir, appInfo, []byte("profile content\n"))
+	c.Assert(err, IsNil)
+	c.Check(fname, Equals, path.Join(dir, "snap.SNAP.APP"))
+	content, err := ioutil.ReadFile(fname)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "profile content\n")
+}
+
+// Tests for SetupProfile()
+
+func (s *appArmorSuite) TestSetupProfileGeneratesWritesAndLoads(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "")
+	defer cmd.Restore()
+	dir := c.MkDir()
+	appInfo := s.appInfo(c)
+	ioutil.WriteFile(s.profilesFilename, []byte("snap.SNAP.APP (enforce)\n"), 0600)
+
+	fname, err := apparmor.SetupProfile(dir, appInfo, apparmor.WithRules("/dev/foo rw,"))
+	c.Assert(err, IsNil)
+	c.Check(fname, Equals, path.Join(dir, "snap.SNAP.APP"))
+
+	content, err := ioutil.ReadFile(fname)
+	c.Assert(err, IsNil)
+	c.Check(string(content), testutil.Contains, "/dev/foo rw,")
+
+	c.Assert(cmd.Calls(), DeepEquals, []string{
+		"--replace --write-cache -O no-expr-simplify --cache-loc=/var/cache/apparmor " + fname})
+}
+
+func (s *appArmorSuite) TestSetupProfilePropagatesLoadErrors(c *C) {
+	cmd := testutil.MockCommand(c, "apparmor_parser", "exit 42")
+	defer cmd.Restore()
+	_, err := apparmor.SetupProfile(c.MkDir(), s.appInfo(c))
+	c.Assert(err.Error(), Equals, `cannot load apparmor profile: exit status 42
+apparmor_parser output:
+`)
+}